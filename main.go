@@ -1,12 +1,37 @@
 package main
 
 import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
 	"encoding/json"
+	"encoding/pem"
 	"fmt"
+	"math/big"
+	"sort"
 
+	"github.com/hyperledger/fabric-chaincode-go/shim"
 	"github.com/hyperledger/fabric-contract-api-go/contractapi"
 )
 
+// Composite key object types used to index tickets and events for
+// efficient range queries. Each index entry stores no payload of its
+// own; the actual record lives under its primary key and is fetched by
+// the ID encoded in the composite key.
+const (
+	ticketByEventStatusIndex = "ticket~event~status~id"
+	ticketByOwnerIndex       = "ticket~owner~id"
+	eventByHostIndex         = "event~host~id"
+
+	// ticketImportedHistoryKey namespaces the provenance record ImportEvent
+	// stores for each ticket it creates, since GetHistoryForKey only ever
+	// reflects this channel's own transaction history and cannot be
+	// replayed onto it directly.
+	ticketImportedHistoryKey = "ticket~importedHistory~id"
+)
+
 // ParticipantType represents the type of participant (Member or EventHost)
 type ParticipantType string
 
@@ -15,7 +40,7 @@ const (
 	EventHost ParticipantType = "EventHost"
 )
 
-// TicketStatus represents the status of a ticket (Available, Sold, Resold, Used)
+// TicketStatus represents the status of a ticket (Available, Sold, Resold, Used, Expired)
 type TicketStatus string
 
 const (
@@ -23,23 +48,47 @@ const (
 	Sold      TicketStatus = "Sold"
 	Resold    TicketStatus = "Resold"
 	Used      TicketStatus = "Used"
+	Expired   TicketStatus = "Expired"
 )
 
-// Participant defines the structure of a participant
+// Participant defines the structure of a participant as stored in the
+// public world state. NameCommitment is SHA-256(name || salt) in hex; the
+// name itself, along with the salt and contact details, lives only in the
+// participantPII private data collection (see ParticipantPII).
 type Participant struct {
-	ID   string          `json:"ID"`
-	Name string          `json:"name"`
-	Type ParticipantType `json:"type"`
+	ID             string          `json:"ID"`
+	Type           ParticipantType `json:"type"`
+	PubKey         string          `json:"pubKey"`
+	NameCommitment string          `json:"nameCommitment"`
 }
 
+// ParticipantPII holds the personally identifiable fields of a
+// participant. It is never written to the public world state; it lives
+// only in the participantPII private data collection, scoped to the
+// organizations named in collections_config.json.
+type ParticipantPII struct {
+	Name    string `json:"name"`
+	Salt    string `json:"salt"`
+	Contact string `json:"contact"`
+}
+
+// participantPIICollection is the private data collection that holds
+// ParticipantPII records, configured in collections_config.json.
+const participantPIICollection = "participantPII"
+
 // Event defines the structure of an event
 type Event struct {
-	ID       string   `json:"ID"`
-	Name     string   `json:"name"`
-	HostID   string   `json:"hostID"`
-	Date     string   `json:"date"`
-	Location string   `json:"location"`
-	Tickets  []string `json:"tickets"`
+	ID           string   `json:"ID"`
+	Name         string   `json:"name"`
+	HostID       string   `json:"hostID"`
+	Date         string   `json:"date"`
+	Location     string   `json:"location"`
+	Tickets      []string `json:"tickets"`
+	Capacity     int      `json:"capacity"`
+	SaleOpensAt  int64    `json:"saleOpensAt"`
+	SaleClosesAt int64    `json:"saleClosesAt"`
+	DoorsOpenAt  int64    `json:"doorsOpenAt"`
+	DoorsCloseAt int64    `json:"doorsCloseAt"`
 }
 
 // Ticket defines the structure of a ticket
@@ -48,6 +97,7 @@ type Ticket struct {
 	EventID string       `json:"eventID"`
 	Status  TicketStatus `json:"status"`
 	Owner   string       `json:"owner"`
+	Nonce   uint64       `json:"nonce"`
 }
 
 // ConcertTicketBookingChaincode is the chaincode implementation
@@ -62,33 +112,132 @@ func (ctbc *ConcertTicketBookingChaincode) Init(ctx contractapi.TransactionConte
 	return nil
 }
 
-// RegisterParticipant registers a participant as either a member or an event host
-func (ctbc *ConcertTicketBookingChaincode) RegisterParticipant(ctx contractapi.TransactionContextInterface, participantID, name string, participantType ParticipantType) error {
-	participant := &Participant{
-		ID:   participantID,
-		Name: name,
-		Type: participantType,
+// RegisterParticipant registers a participant as either a member or an
+// event host. pubKeyPEM is the participant's PEM-encoded secp256r1 public
+// key, used to verify transfer authorizations for tickets they own. The
+// participant's name, salt and contact details are read from the
+// "participant_pii" transient field (JSON-encoded ParticipantPII) so they
+// never appear in the public transaction proposal or world state: only a
+// SHA-256(name || salt) commitment is stored publicly, while the PII
+// itself goes to the participantPII private data collection.
+func (ctbc *ConcertTicketBookingChaincode) RegisterParticipant(ctx contractapi.TransactionContextInterface, participantID string, participantType ParticipantType, pubKeyPEM string) error {
+	if _, err := parseECDSAPublicKey(pubKeyPEM); err != nil {
+		return fmt.Errorf("invalid public key for participant %s: %v", participantID, err)
 	}
 
-	// Save the participant to the world state
-	err := ctx.GetStub().PutState(participantID, participantToBytes(participant))
+	transientMap, err := ctx.GetStub().GetTransient()
 	if err != nil {
+		return fmt.Errorf("failed to read transient data: %v", err)
+	}
+	piiBytes, ok := transientMap["participant_pii"]
+	if !ok {
+		return fmt.Errorf("missing \"participant_pii\" transient field")
+	}
+
+	pii := &ParticipantPII{}
+	if err := json.Unmarshal(piiBytes, pii); err != nil {
+		return fmt.Errorf("failed to unmarshal participant PII: %v", err)
+	}
+	if pii.Name == "" || pii.Salt == "" {
+		return fmt.Errorf("participant PII must include a name and salt")
+	}
+
+	participant := &Participant{
+		ID:             participantID,
+		Type:           participantType,
+		PubKey:         pubKeyPEM,
+		NameCommitment: nameCommitment(pii.Name, pii.Salt),
+	}
+
+	// Save the public participant record to the world state
+	if err := ctx.GetStub().PutState(participantID, participantToBytes(participant)); err != nil {
 		return fmt.Errorf("failed to put participant: %v", err)
 	}
 
+	if err := ctx.GetStub().PutPrivateData(participantPIICollection, participantID, piiBytes); err != nil {
+		return fmt.Errorf("failed to put participant PII: %v", err)
+	}
+
 	fmt.Printf("Participant with ID '%s' registered as a '%s'\n", participantID, participantType)
 	return nil
 }
 
+// GetParticipantPII reads a participant's PII record from the
+// participantPII private data collection. It returns an error if the
+// calling organization is not part of the collection and therefore has no
+// access to the data, or if no PII has been recorded for participantID.
+func (ctbc *ConcertTicketBookingChaincode) GetParticipantPII(ctx contractapi.TransactionContextInterface, participantID string) (*ParticipantPII, error) {
+	piiBytes, err := ctx.GetStub().GetPrivateData(participantPIICollection, participantID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read participant PII for %s (your organization may not have access to this collection): %v", participantID, err)
+	}
+	if piiBytes == nil {
+		return nil, fmt.Errorf("no PII available for participant %s", participantID)
+	}
+
+	pii := &ParticipantPII{}
+	if err := json.Unmarshal(piiBytes, pii); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal participant PII: %v", err)
+	}
+
+	return pii, nil
+}
+
+// VerifyParticipantName recomputes the SHA-256(name || salt) commitment
+// for the supplied name and salt and compares it against the public
+// NameCommitment stored for participantID, without requiring access to
+// the participantPII private data collection.
+func (ctbc *ConcertTicketBookingChaincode) VerifyParticipantName(ctx contractapi.TransactionContextInterface, participantID, name, salt string) (bool, error) {
+	participantBytes, err := ctx.GetStub().GetState(participantID)
+	if err != nil {
+		return false, fmt.Errorf("failed to read participant %s: %v", participantID, err)
+	}
+	if participantBytes == nil {
+		return false, fmt.Errorf("participant %s does not exist", participantID)
+	}
+
+	participant := &Participant{}
+	if err := bytesToParticipant(participantBytes, participant); err != nil {
+		return false, err
+	}
+
+	return nameCommitment(name, salt) == participant.NameCommitment, nil
+}
+
+// nameCommitment computes the public commitment for a participant's name:
+// hex(SHA-256(name || salt)).
+func nameCommitment(name, salt string) string {
+	sum := sha256.Sum256([]byte(name + salt))
+	return hex.EncodeToString(sum[:])
+}
+
 // CreateEvent creates a new event with the provided details
-func (ctbc *ConcertTicketBookingChaincode) CreateEvent(ctx contractapi.TransactionContextInterface, eventID, eventName, hostID, eventDate, location string) error {
+func (ctbc *ConcertTicketBookingChaincode) CreateEvent(ctx contractapi.TransactionContextInterface, eventID, eventName, hostID, eventDate, location string, capacity int, saleOpensAt, saleClosesAt, doorsOpenAt, doorsCloseAt int64) error {
+	if capacity <= 0 {
+		return fmt.Errorf("capacity must be a positive number of tickets")
+	}
+	if saleOpensAt >= saleClosesAt {
+		return fmt.Errorf("saleOpensAt must be before saleClosesAt")
+	}
+	if doorsOpenAt >= doorsCloseAt {
+		return fmt.Errorf("doorsOpenAt must be before doorsCloseAt")
+	}
+	if doorsOpenAt < saleOpensAt {
+		return fmt.Errorf("doorsOpenAt cannot be before saleOpensAt")
+	}
+
 	event := &Event{
-		ID:       eventID,
-		Name:     eventName,
-		HostID:   hostID,
-		Date:     eventDate,
-		Location: location,
-		Tickets:  []string{},
+		ID:           eventID,
+		Name:         eventName,
+		HostID:       hostID,
+		Date:         eventDate,
+		Location:     location,
+		Tickets:      []string{},
+		Capacity:     capacity,
+		SaleOpensAt:  saleOpensAt,
+		SaleClosesAt: saleClosesAt,
+		DoorsOpenAt:  doorsOpenAt,
+		DoorsCloseAt: doorsCloseAt,
 	}
 
 	// Save the event to the world state
@@ -97,10 +246,79 @@ func (ctbc *ConcertTicketBookingChaincode) CreateEvent(ctx contractapi.Transacti
 		return fmt.Errorf("failed to put event: %v", err)
 	}
 
+	if err := putEventByHostIndex(ctx, hostID, eventID); err != nil {
+		return err
+	}
+
 	fmt.Printf("Event with ID '%s' created\n", eventID)
 	return nil
 }
 
+// MintTickets creates count new tickets for eventID, appends them to the
+// event's ticket list and indexes each one as Available. Ticket IDs are
+// derived from the event ID and the event's current ticket count so that
+// repeated calls never collide. Minting is rejected once it would push
+// len(event.Tickets) past event.Capacity, so a fully minted event always
+// holds the invariant Capacity == len(Tickets).
+func (ctbc *ConcertTicketBookingChaincode) MintTickets(ctx contractapi.TransactionContextInterface, eventID string, count int) error {
+	if count <= 0 {
+		return fmt.Errorf("count must be a positive number of tickets to mint")
+	}
+
+	eventBytes, err := ctx.GetStub().GetState(eventID)
+	if err != nil {
+		return fmt.Errorf("failed to read event %s: %v", eventID, err)
+	}
+	if eventBytes == nil {
+		return fmt.Errorf("event %s does not exist", eventID)
+	}
+
+	event := &Event{}
+	if err := bytesToEvent(eventBytes, event); err != nil {
+		return err
+	}
+
+	if len(event.Tickets)+count > event.Capacity {
+		return fmt.Errorf("minting %d ticket(s) would exceed event %s's capacity of %d", count, eventID, event.Capacity)
+	}
+
+	for i := 0; i < count; i++ {
+		ticketID := fmt.Sprintf("%s-ticket-%d", eventID, len(event.Tickets)+1)
+
+		existing, err := ctx.GetStub().GetState(ticketID)
+		if err != nil {
+			return fmt.Errorf("failed to check ticket %s: %v", ticketID, err)
+		}
+		if existing != nil {
+			return fmt.Errorf("ticket %s already exists", ticketID)
+		}
+
+		ticket := &Ticket{
+			ID:      ticketID,
+			EventID: eventID,
+			Status:  Available,
+			Owner:   "",
+		}
+
+		if err := ctx.GetStub().PutState(ticketID, ticketToBytes(ticket)); err != nil {
+			return fmt.Errorf("failed to put ticket: %v", err)
+		}
+
+		if err := putTicketByEventStatusIndex(ctx, ticket); err != nil {
+			return err
+		}
+
+		event.Tickets = append(event.Tickets, ticketID)
+	}
+
+	if err := ctx.GetStub().PutState(eventID, eventToBytes(event)); err != nil {
+		return fmt.Errorf("failed to update event: %v", err)
+	}
+
+	fmt.Printf("Minted %d ticket(s) for event '%s'\n", count, eventID)
+	return nil
+}
+
 // ListAvailableTickets lists all available tickets for the given event
 func (ctbc *ConcertTicketBookingChaincode) ListAvailableTickets(ctx contractapi.TransactionContextInterface, eventID string) ([]string, error) {
 	eventBytes, err := ctx.GetStub().GetState(eventID)
@@ -158,6 +376,14 @@ func (ctbc *ConcertTicketBookingChaincode) SellTicket(ctx contractapi.Transactio
 		return fmt.Errorf("ticket %s is not available for sale", ticketID)
 	}
 
+	if err := requireWithinSaleWindow(ctx, ticket.EventID); err != nil {
+		return err
+	}
+
+	if err := deleteTicketByEventStatusIndex(ctx, ticket); err != nil {
+		return err
+	}
+
 	ticket.Status = Sold
 	ticket.Owner = participantID
 
@@ -167,12 +393,28 @@ func (ctbc *ConcertTicketBookingChaincode) SellTicket(ctx contractapi.Transactio
 		return fmt.Errorf("failed to update ticket: %v", err)
 	}
 
+	if err := putTicketByEventStatusIndex(ctx, ticket); err != nil {
+		return err
+	}
+	if err := putTicketByOwnerIndex(ctx, ticket); err != nil {
+		return err
+	}
+
 	fmt.Printf("Ticket with ID '%s' sold to participant with ID '%s'\n", ticketID, participantID)
 	return nil
 }
 
-// ResellTicket resells a ticket from the current owner to the specified participant
-func (ctbc *ConcertTicketBookingChaincode) ResellTicket(ctx contractapi.TransactionContextInterface, ticketID, newOwnerID string) error {
+// ResellTicketSigned resells a ticket from its current owner to a new
+// owner, but only after verifying that the current owner authorized the
+// transfer by signing (ticketID, newOwnerID, nonce, expiry) with their
+// registered public key. This is the only path for reassigning a sold
+// ticket's owner: an unauthenticated ResellTicket would let any caller
+// rewrite Owner with no proof of consent, so it does not exist. nonce
+// must match the ticket's stored nonce to prevent a captured signature
+// from being replayed, and expiry must not have passed as of the
+// transaction timestamp. On success a TicketTransferred chaincode event
+// is emitted so off-chain indexers can track ownership history.
+func (ctbc *ConcertTicketBookingChaincode) ResellTicketSigned(ctx contractapi.TransactionContextInterface, ticketID, newOwnerID string, nonce uint64, expiry int64, sigHex string) error {
 	ticketBytes, err := ctx.GetStub().GetState(ticketID)
 	if err != nil {
 		return fmt.Errorf("failed to read ticket %s: %v", ticketID, err)
@@ -182,8 +424,7 @@ func (ctbc *ConcertTicketBookingChaincode) ResellTicket(ctx contractapi.Transact
 	}
 
 	ticket := &Ticket{}
-	err = bytesToTicket(ticketBytes, ticket)
-	if err != nil {
+	if err := bytesToTicket(ticketBytes, ticket); err != nil {
 		return err
 	}
 
@@ -191,16 +432,66 @@ func (ctbc *ConcertTicketBookingChaincode) ResellTicket(ctx contractapi.Transact
 		return fmt.Errorf("ticket %s is not sold and cannot be resold", ticketID)
 	}
 
+	if err := requireWithinSaleWindow(ctx, ticket.EventID); err != nil {
+		return err
+	}
+
+	if nonce != ticket.Nonce {
+		return fmt.Errorf("stale nonce for ticket %s: expected %d, got %d", ticketID, ticket.Nonce, nonce)
+	}
+
+	txTimestamp, err := ctx.GetStub().GetTxTimestamp()
+	if err != nil {
+		return fmt.Errorf("failed to read transaction timestamp: %v", err)
+	}
+	if expiry < txTimestamp.GetSeconds() {
+		return fmt.Errorf("transfer authorization for ticket %s has expired", ticketID)
+	}
+
+	ownerBytes, err := ctx.GetStub().GetState(ticket.Owner)
+	if err != nil {
+		return fmt.Errorf("failed to read participant %s: %v", ticket.Owner, err)
+	}
+	if ownerBytes == nil {
+		return fmt.Errorf("participant %s does not exist", ticket.Owner)
+	}
+
+	owner := &Participant{}
+	if err := bytesToParticipant(ownerBytes, owner); err != nil {
+		return err
+	}
+
+	if err := verifyTransferSignature(owner.PubKey, ticketID, newOwnerID, nonce, expiry, sigHex); err != nil {
+		return fmt.Errorf("transfer authorization rejected for ticket %s: %v", ticketID, err)
+	}
+
+	if err := deleteTicketByEventStatusIndex(ctx, ticket); err != nil {
+		return err
+	}
+	if err := deleteTicketByOwnerIndex(ctx, ticket); err != nil {
+		return err
+	}
+
 	ticket.Status = Resold
 	ticket.Owner = newOwnerID
+	ticket.Nonce++
 
-	// Update the ticket in the world state
-	err = ctx.GetStub().PutState(ticketID, ticketToBytes(ticket))
-	if err != nil {
+	if err := ctx.GetStub().PutState(ticketID, ticketToBytes(ticket)); err != nil {
 		return fmt.Errorf("failed to update ticket: %v", err)
 	}
 
-	fmt.Printf("Ticket with ID '%s' resold to participant with ID '%s'\n", ticketID, newOwnerID)
+	if err := putTicketByEventStatusIndex(ctx, ticket); err != nil {
+		return err
+	}
+	if err := putTicketByOwnerIndex(ctx, ticket); err != nil {
+		return err
+	}
+
+	if err := emitTicketStatusEvent(ctx, "TicketTransferred", ticket); err != nil {
+		return err
+	}
+
+	fmt.Printf("Ticket with ID '%s' resold to participant with ID '%s' via signed authorization\n", ticketID, newOwnerID)
 	return nil
 }
 
@@ -240,6 +531,42 @@ func (ctbc *ConcertTicketBookingChaincode) UseTicket(ctx contractapi.Transaction
 
 	for _, ticketID := range event.Tickets {
 		if ticketID == ticket.ID {
+			txTimestamp, err := ctx.GetStub().GetTxTimestamp()
+			if err != nil {
+				return fmt.Errorf("failed to read transaction timestamp: %v", err)
+			}
+			txSeconds := txTimestamp.GetSeconds()
+
+			if txSeconds < event.DoorsOpenAt {
+				return fmt.Errorf("doors for event %s are not open yet", eventID)
+			}
+
+			if err := deleteTicketByEventStatusIndex(ctx, ticket); err != nil {
+				return err
+			}
+
+			if txSeconds > event.DoorsCloseAt {
+				// A returned error would abort the transaction and discard
+				// this write along with it, so the expiry is committed by
+				// returning nil: the ticket is rejected for entry by being
+				// left (and recorded) as Expired rather than Used, not by
+				// failing the transaction.
+				ticket.Status = Expired
+
+				if err := ctx.GetStub().PutState(ticketID, ticketToBytes(ticket)); err != nil {
+					return fmt.Errorf("failed to update ticket: %v", err)
+				}
+				if err := putTicketByEventStatusIndex(ctx, ticket); err != nil {
+					return err
+				}
+				if err := emitTicketStatusEvent(ctx, "TicketExpired", ticket); err != nil {
+					return err
+				}
+
+				fmt.Printf("Ticket with ID '%s' rejected for event with ID '%s': doors are closed, ticket marked Expired\n", ticketID, eventID)
+				return nil
+			}
+
 			ticket.Status = Used
 			// Update the ticket in the world state
 			err = ctx.GetStub().PutState(ticketID, ticketToBytes(ticket))
@@ -247,6 +574,13 @@ func (ctbc *ConcertTicketBookingChaincode) UseTicket(ctx contractapi.Transaction
 				return fmt.Errorf("failed to update ticket: %v", err)
 			}
 
+			if err := putTicketByEventStatusIndex(ctx, ticket); err != nil {
+				return err
+			}
+			if err := emitTicketStatusEvent(ctx, "TicketUsed", ticket); err != nil {
+				return err
+			}
+
 			fmt.Printf("Ticket with ID '%s' used for event with ID '%s'\n", ticketID, eventID)
 			return nil
 		}
@@ -255,6 +589,817 @@ func (ctbc *ConcertTicketBookingChaincode) UseTicket(ctx contractapi.Transaction
 	return fmt.Errorf("ticket %s does not belong to event %s", ticketID, eventID)
 }
 
+// ExpireUnsoldTickets marks every still-Available ticket for eventID as
+// Expired. It may only run once the event's sale window has closed, and
+// is meant to be driven by an off-chain scheduler watching the
+// SaleClosesAt deadline rather than called inline from a purchase flow.
+func (ctbc *ConcertTicketBookingChaincode) ExpireUnsoldTickets(ctx contractapi.TransactionContextInterface, eventID string) error {
+	event, err := loadEvent(ctx, eventID)
+	if err != nil {
+		return err
+	}
+
+	txTimestamp, err := ctx.GetStub().GetTxTimestamp()
+	if err != nil {
+		return fmt.Errorf("failed to read transaction timestamp: %v", err)
+	}
+	if txTimestamp.GetSeconds() <= event.SaleClosesAt {
+		return fmt.Errorf("sale window for event %s has not closed yet", eventID)
+	}
+
+	ticketIDs, err := collectTicketIDsByEventStatus(ctx, eventID, Available)
+	if err != nil {
+		return err
+	}
+
+	for _, ticketID := range ticketIDs {
+		if err := expireTicket(ctx, ticketID); err != nil {
+			return err
+		}
+	}
+
+	// Fabric allows only one SetEvent per transaction, so a single
+	// aggregate event carries every expired ticket ID rather than one
+	// event per ticket (which would leave only the last one observable).
+	if err := emitTicketIDsEvent(ctx, "UnsoldTicketsExpired", eventID, ticketIDs); err != nil {
+		return err
+	}
+
+	fmt.Printf("Expired %d unsold ticket(s) for event '%s'\n", len(ticketIDs), eventID)
+	return nil
+}
+
+// SweepUnusedTickets marks every Sold or Resold ticket for eventID as
+// Expired. It is intended for post-event cleanup once DoorsCloseAt has
+// passed, reclaiming tickets that were bought but never scanned at entry.
+func (ctbc *ConcertTicketBookingChaincode) SweepUnusedTickets(ctx contractapi.TransactionContextInterface, eventID string) error {
+	event, err := loadEvent(ctx, eventID)
+	if err != nil {
+		return err
+	}
+
+	txTimestamp, err := ctx.GetStub().GetTxTimestamp()
+	if err != nil {
+		return fmt.Errorf("failed to read transaction timestamp: %v", err)
+	}
+	if txTimestamp.GetSeconds() <= event.DoorsCloseAt {
+		return fmt.Errorf("doors for event %s have not closed yet", eventID)
+	}
+
+	var swept []string
+	for _, status := range []TicketStatus{Sold, Resold} {
+		ticketIDs, err := collectTicketIDsByEventStatus(ctx, eventID, status)
+		if err != nil {
+			return err
+		}
+		for _, ticketID := range ticketIDs {
+			if err := expireTicket(ctx, ticketID); err != nil {
+				return err
+			}
+			swept = append(swept, ticketID)
+		}
+	}
+
+	// Fabric allows only one SetEvent per transaction, so a single
+	// aggregate event carries every swept ticket ID rather than one event
+	// per ticket (which would leave only the last one observable).
+	if err := emitTicketIDsEvent(ctx, "UnusedTicketsSwept", eventID, swept); err != nil {
+		return err
+	}
+
+	fmt.Printf("Swept %d unused ticket(s) for event '%s'\n", len(swept), eventID)
+	return nil
+}
+
+// loadEvent reads and unmarshals eventID, returning an error if it does
+// not exist.
+func loadEvent(ctx contractapi.TransactionContextInterface, eventID string) (*Event, error) {
+	eventBytes, err := ctx.GetStub().GetState(eventID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read event %s: %v", eventID, err)
+	}
+	if eventBytes == nil {
+		return nil, fmt.Errorf("event %s does not exist", eventID)
+	}
+
+	event := &Event{}
+	if err := bytesToEvent(eventBytes, event); err != nil {
+		return nil, err
+	}
+	return event, nil
+}
+
+// collectTicketIDsByEventStatus returns every ticket ID currently indexed
+// under eventID with the given status, via the ticket~event~status~id
+// index. Unlike QueryTicketsByEventAndStatus it is not paginated, since
+// sweeps are administrative operations that need the full set.
+func collectTicketIDsByEventStatus(ctx contractapi.TransactionContextInterface, eventID string, status TicketStatus) ([]string, error) {
+	iterator, err := ctx.GetStub().GetStateByPartialCompositeKey(ticketByEventStatusIndex, []string{eventID, string(status)})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query tickets for event %s with status %s: %v", eventID, status, err)
+	}
+	defer iterator.Close()
+
+	ticketIDs := []string{}
+	for iterator.HasNext() {
+		item, err := iterator.Next()
+		if err != nil {
+			return nil, fmt.Errorf("failed to iterate ticket index: %v", err)
+		}
+
+		_, parts, err := ctx.GetStub().SplitCompositeKey(item.Key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to split composite key %s: %v", item.Key, err)
+		}
+		ticketIDs = append(ticketIDs, parts[len(parts)-1])
+	}
+
+	return ticketIDs, nil
+}
+
+// expireTicket loads ticketID, transitions it to Expired and rewrites its
+// event/status index entry. It does not emit an event itself: it is only
+// ever called in a loop by ExpireUnsoldTickets/SweepUnusedTickets, which
+// emit a single aggregate event for the whole batch once the loop
+// completes, since Fabric only honors the last SetEvent in a transaction.
+func expireTicket(ctx contractapi.TransactionContextInterface, ticketID string) error {
+	ticketBytes, err := ctx.GetStub().GetState(ticketID)
+	if err != nil {
+		return fmt.Errorf("failed to read ticket %s: %v", ticketID, err)
+	}
+	if ticketBytes == nil {
+		return fmt.Errorf("ticket %s does not exist", ticketID)
+	}
+
+	ticket := &Ticket{}
+	if err := bytesToTicket(ticketBytes, ticket); err != nil {
+		return err
+	}
+
+	if err := deleteTicketByEventStatusIndex(ctx, ticket); err != nil {
+		return err
+	}
+
+	ticket.Status = Expired
+
+	if err := ctx.GetStub().PutState(ticketID, ticketToBytes(ticket)); err != nil {
+		return fmt.Errorf("failed to update ticket: %v", err)
+	}
+	if err := putTicketByEventStatusIndex(ctx, ticket); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// requireWithinSaleWindow rejects the current transaction if its timestamp
+// falls outside eventID's [SaleOpensAt, SaleClosesAt] window.
+func requireWithinSaleWindow(ctx contractapi.TransactionContextInterface, eventID string) error {
+	event, err := loadEvent(ctx, eventID)
+	if err != nil {
+		return err
+	}
+
+	txTimestamp, err := ctx.GetStub().GetTxTimestamp()
+	if err != nil {
+		return fmt.Errorf("failed to read transaction timestamp: %v", err)
+	}
+	seconds := txTimestamp.GetSeconds()
+
+	if seconds < event.SaleOpensAt {
+		return fmt.Errorf("ticket sales for event %s have not opened yet", eventID)
+	}
+	if seconds > event.SaleClosesAt {
+		return fmt.Errorf("ticket sales for event %s have closed", eventID)
+	}
+
+	return nil
+}
+
+// emitTicketStatusEvent emits a chaincode event named eventName carrying
+// ticket's current state, so an off-chain scheduler or indexer can track
+// ticket lifecycle transitions without polling the ledger.
+func emitTicketStatusEvent(ctx contractapi.TransactionContextInterface, eventName string, ticket *Ticket) error {
+	payload, err := json.Marshal(ticket)
+	if err != nil {
+		return fmt.Errorf("failed to marshal %s event: %v", eventName, err)
+	}
+	if err := ctx.GetStub().SetEvent(eventName, payload); err != nil {
+		return fmt.Errorf("failed to emit %s event: %v", eventName, err)
+	}
+	return nil
+}
+
+// TicketIDsEvent is the payload for a batch lifecycle transition covering
+// every ticket ID in a single sweep, since a chaincode transaction can
+// only emit one event, not one per ticket.
+type TicketIDsEvent struct {
+	EventID   string   `json:"eventID"`
+	TicketIDs []string `json:"ticketIDs"`
+}
+
+// emitTicketIDsEvent emits a single chaincode event named eventName
+// carrying every ticket ID touched by a batch operation on eventID.
+func emitTicketIDsEvent(ctx contractapi.TransactionContextInterface, eventName, eventID string, ticketIDs []string) error {
+	payload, err := json.Marshal(&TicketIDsEvent{EventID: eventID, TicketIDs: ticketIDs})
+	if err != nil {
+		return fmt.Errorf("failed to marshal %s event: %v", eventName, err)
+	}
+	if err := ctx.GetStub().SetEvent(eventName, payload); err != nil {
+		return fmt.Errorf("failed to emit %s event: %v", eventName, err)
+	}
+	return nil
+}
+
+// eventExportSchemaVersion is bumped whenever the shape of EventExport
+// changes in a way that ImportEvent needs to handle explicitly.
+const eventExportSchemaVersion = 1
+
+// TicketHistoryEntry is one entry from GetHistoryForKey for a ticket: the
+// transaction that wrote it, when, and the resulting value (nil if the
+// write was a delete).
+type TicketHistoryEntry struct {
+	TxID      string  `json:"txID"`
+	Timestamp int64   `json:"timestamp"`
+	IsDelete  bool    `json:"isDelete"`
+	Ticket    *Ticket `json:"ticket,omitempty"`
+}
+
+// TicketExport bundles a ticket's current state with its full write
+// history so an import can reconstruct its provenance.
+type TicketExport struct {
+	Ticket  *Ticket              `json:"ticket"`
+	History []TicketHistoryEntry `json:"history"`
+}
+
+// EventExport is a self-contained dump of an event, its tickets, their
+// ownership history, and every participant touched along the way. Hash is
+// a SHA-256 hex digest of the dump with Hash itself cleared, so ImportEvent
+// can detect tampering or truncation in transit.
+type EventExport struct {
+	SchemaVersion int            `json:"schemaVersion"`
+	Event         *Event         `json:"event"`
+	Tickets       []TicketExport `json:"tickets"`
+	Participants  []*Participant `json:"participants"`
+	Hash          string         `json:"hash"`
+}
+
+// ExportEvent walks eventID, all of its tickets (with their full
+// GetHistoryForKey provenance), and every participant referenced as a
+// host or an owner, and returns the result as a hashed JSON dump suitable
+// for ImportEvent on another channel or network.
+func (ctbc *ConcertTicketBookingChaincode) ExportEvent(ctx contractapi.TransactionContextInterface, eventID string) (string, error) {
+	eventBytes, err := ctx.GetStub().GetState(eventID)
+	if err != nil {
+		return "", fmt.Errorf("failed to read event %s: %v", eventID, err)
+	}
+	if eventBytes == nil {
+		return "", fmt.Errorf("event %s does not exist", eventID)
+	}
+
+	event := &Event{}
+	if err := bytesToEvent(eventBytes, event); err != nil {
+		return "", err
+	}
+
+	touchedParticipants := map[string]bool{event.HostID: true}
+	ticketExports := make([]TicketExport, 0, len(event.Tickets))
+
+	for _, ticketID := range event.Tickets {
+		ticketBytes, err := ctx.GetStub().GetState(ticketID)
+		if err != nil {
+			return "", fmt.Errorf("failed to read ticket %s: %v", ticketID, err)
+		}
+		if ticketBytes == nil {
+			return "", fmt.Errorf("ticket %s does not exist", ticketID)
+		}
+
+		ticket := &Ticket{}
+		if err := bytesToTicket(ticketBytes, ticket); err != nil {
+			return "", err
+		}
+		if ticket.Owner != "" {
+			touchedParticipants[ticket.Owner] = true
+		}
+
+		history, err := exportTicketHistory(ctx, ticketID, touchedParticipants)
+		if err != nil {
+			return "", err
+		}
+
+		ticketExports = append(ticketExports, TicketExport{Ticket: ticket, History: history})
+	}
+
+	participantIDs := make([]string, 0, len(touchedParticipants))
+	for id := range touchedParticipants {
+		if id != "" {
+			participantIDs = append(participantIDs, id)
+		}
+	}
+	sort.Strings(participantIDs)
+
+	participants := make([]*Participant, 0, len(participantIDs))
+	for _, id := range participantIDs {
+		participantBytes, err := ctx.GetStub().GetState(id)
+		if err != nil {
+			return "", fmt.Errorf("failed to read participant %s: %v", id, err)
+		}
+		if participantBytes == nil {
+			continue
+		}
+
+		participant := &Participant{}
+		if err := bytesToParticipant(participantBytes, participant); err != nil {
+			return "", err
+		}
+		participants = append(participants, participant)
+	}
+
+	dump := &EventExport{
+		SchemaVersion: eventExportSchemaVersion,
+		Event:         event,
+		Tickets:       ticketExports,
+		Participants:  participants,
+	}
+
+	unsignedDump, err := json.Marshal(dump)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal event export: %v", err)
+	}
+	hash := sha256.Sum256(unsignedDump)
+	dump.Hash = hex.EncodeToString(hash[:])
+
+	signedDump, err := json.Marshal(dump)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal event export: %v", err)
+	}
+
+	return string(signedDump), nil
+}
+
+// exportTicketHistory reads the full GetHistoryForKey log for ticketID and
+// records every owner it encounters into touchedParticipants.
+func exportTicketHistory(ctx contractapi.TransactionContextInterface, ticketID string, touchedParticipants map[string]bool) ([]TicketHistoryEntry, error) {
+	iterator, err := ctx.GetStub().GetHistoryForKey(ticketID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read history for ticket %s: %v", ticketID, err)
+	}
+	defer iterator.Close()
+
+	history := []TicketHistoryEntry{}
+	for iterator.HasNext() {
+		mod, err := iterator.Next()
+		if err != nil {
+			return nil, fmt.Errorf("failed to iterate history for ticket %s: %v", ticketID, err)
+		}
+
+		entry := TicketHistoryEntry{
+			TxID:     mod.GetTxId(),
+			IsDelete: mod.GetIsDelete(),
+		}
+		if mod.GetTimestamp() != nil {
+			entry.Timestamp = mod.GetTimestamp().GetSeconds()
+		}
+
+		if !mod.GetIsDelete() && len(mod.GetValue()) > 0 {
+			historicTicket := &Ticket{}
+			if err := bytesToTicket(mod.GetValue(), historicTicket); err != nil {
+				return nil, err
+			}
+			entry.Ticket = historicTicket
+			if historicTicket.Owner != "" {
+				touchedParticipants[historicTicket.Owner] = true
+			}
+		}
+
+		history = append(history, entry)
+	}
+
+	return history, nil
+}
+
+// ImportEvent validates the integrity hash and structure of a dump
+// produced by ExportEvent, rewrites the event and ticket IDs to
+// newEventID so they cannot collide with anything already in this
+// channel's world state, and recreates the event, its tickets (at their
+// final lifecycle state) and any participants not already known here, so
+// the resulting world state is indistinguishable from having run the
+// original transactions. Each ticket's pre-import GetHistoryForKey
+// provenance is preserved via putImportedTicketHistory/
+// GetImportedTicketHistory, since Fabric's own history log is generated
+// by the ledger and cannot be replayed onto this channel directly.
+func (ctbc *ConcertTicketBookingChaincode) ImportEvent(ctx contractapi.TransactionContextInterface, dumpJSON string, newEventID string) error {
+	dump := &EventExport{}
+	if err := json.Unmarshal([]byte(dumpJSON), dump); err != nil {
+		return fmt.Errorf("failed to unmarshal event export: %v", err)
+	}
+
+	claimedHash := dump.Hash
+	dump.Hash = ""
+	unsignedDump, err := json.Marshal(dump)
+	if err != nil {
+		return fmt.Errorf("failed to re-marshal event export: %v", err)
+	}
+	recomputedHash := sha256.Sum256(unsignedDump)
+	if hex.EncodeToString(recomputedHash[:]) != claimedHash {
+		return fmt.Errorf("event export failed integrity check: hash mismatch")
+	}
+
+	// The hash only proves the dump wasn't truncated or altered in
+	// transit, not that the producer populated it honestly, so every
+	// field dereferenced below must be validated to exist first.
+	if dump.SchemaVersion != eventExportSchemaVersion {
+		return fmt.Errorf("unsupported event export schema version %d", dump.SchemaVersion)
+	}
+	if dump.Event == nil {
+		return fmt.Errorf("event export is missing its event record")
+	}
+	for i, ticketExport := range dump.Tickets {
+		if ticketExport.Ticket == nil {
+			return fmt.Errorf("event export ticket entry %d is missing its ticket record", i)
+		}
+	}
+	for i, participant := range dump.Participants {
+		if participant == nil {
+			return fmt.Errorf("event export participant entry %d is missing its participant record", i)
+		}
+	}
+
+	existingEvent, err := ctx.GetStub().GetState(newEventID)
+	if err != nil {
+		return fmt.Errorf("failed to check event %s: %v", newEventID, err)
+	}
+	if existingEvent != nil {
+		return fmt.Errorf("event %s already exists", newEventID)
+	}
+
+	for _, participant := range dump.Participants {
+		existingParticipant, err := ctx.GetStub().GetState(participant.ID)
+		if err != nil {
+			return fmt.Errorf("failed to check participant %s: %v", participant.ID, err)
+		}
+		if existingParticipant == nil {
+			if err := ctx.GetStub().PutState(participant.ID, participantToBytes(participant)); err != nil {
+				return fmt.Errorf("failed to put participant: %v", err)
+			}
+		}
+	}
+
+	newTicketIDs := make([]string, len(dump.Tickets))
+	for i := range dump.Tickets {
+		newTicketIDs[i] = fmt.Sprintf("%s-ticket-%d", newEventID, i+1)
+
+		existingTicket, err := ctx.GetStub().GetState(newTicketIDs[i])
+		if err != nil {
+			return fmt.Errorf("failed to check ticket %s: %v", newTicketIDs[i], err)
+		}
+		if existingTicket != nil {
+			return fmt.Errorf("ticket %s already exists", newTicketIDs[i])
+		}
+	}
+
+	newEvent := &Event{
+		ID:           newEventID,
+		Name:         dump.Event.Name,
+		HostID:       dump.Event.HostID,
+		Date:         dump.Event.Date,
+		Location:     dump.Event.Location,
+		Tickets:      newTicketIDs,
+		Capacity:     dump.Event.Capacity,
+		SaleOpensAt:  dump.Event.SaleOpensAt,
+		SaleClosesAt: dump.Event.SaleClosesAt,
+		DoorsOpenAt:  dump.Event.DoorsOpenAt,
+		DoorsCloseAt: dump.Event.DoorsCloseAt,
+	}
+	if err := ctx.GetStub().PutState(newEventID, eventToBytes(newEvent)); err != nil {
+		return fmt.Errorf("failed to put event: %v", err)
+	}
+	if err := putEventByHostIndex(ctx, newEvent.HostID, newEventID); err != nil {
+		return err
+	}
+
+	for i, ticketExport := range dump.Tickets {
+		newTicket := &Ticket{
+			ID:      newTicketIDs[i],
+			EventID: newEventID,
+			Status:  ticketExport.Ticket.Status,
+			Owner:   ticketExport.Ticket.Owner,
+			Nonce:   ticketExport.Ticket.Nonce,
+		}
+
+		if err := ctx.GetStub().PutState(newTicket.ID, ticketToBytes(newTicket)); err != nil {
+			return fmt.Errorf("failed to put ticket: %v", err)
+		}
+		if err := putTicketByEventStatusIndex(ctx, newTicket); err != nil {
+			return err
+		}
+		if err := putTicketByOwnerIndex(ctx, newTicket); err != nil {
+			return err
+		}
+		if err := putImportedTicketHistory(ctx, newTicket.ID, ticketExport.History); err != nil {
+			return err
+		}
+	}
+
+	fmt.Printf("Imported event '%s' as '%s' with %d ticket(s)\n", dump.Event.ID, newEventID, len(newTicketIDs))
+	return nil
+}
+
+// TicketQueryResult is a page of tickets returned by a composite-key range
+// query, along with the bookmark needed to fetch the next page.
+type TicketQueryResult struct {
+	Tickets  []*Ticket `json:"tickets"`
+	Bookmark string    `json:"bookmark"`
+}
+
+// EventQueryResult is a page of events returned by a composite-key range
+// query, along with the bookmark needed to fetch the next page.
+type EventQueryResult struct {
+	Events   []*Event `json:"events"`
+	Bookmark string   `json:"bookmark"`
+}
+
+// QueryTicketsByOwner returns a page of tickets currently held by ownerID,
+// using the ticket~owner~id index so the chaincode never has to scan
+// every ticket in the channel.
+func (ctbc *ConcertTicketBookingChaincode) QueryTicketsByOwner(ctx contractapi.TransactionContextInterface, ownerID string, pageSize int32, bookmark string) (*TicketQueryResult, error) {
+	iterator, metadata, err := ctx.GetStub().GetStateByPartialCompositeKeyWithPagination(ticketByOwnerIndex, []string{ownerID}, pageSize, bookmark)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query tickets by owner %s: %v", ownerID, err)
+	}
+	defer iterator.Close()
+
+	tickets, err := resolveTicketsFromIndex(ctx, iterator, ticketByOwnerIndex)
+	if err != nil {
+		return nil, err
+	}
+
+	return &TicketQueryResult{Tickets: tickets, Bookmark: metadata.GetBookmark()}, nil
+}
+
+// QueryTicketsByEventAndStatus returns a page of tickets for eventID whose
+// status matches status, using the ticket~event~status~id index.
+func (ctbc *ConcertTicketBookingChaincode) QueryTicketsByEventAndStatus(ctx contractapi.TransactionContextInterface, eventID string, status TicketStatus, pageSize int32, bookmark string) (*TicketQueryResult, error) {
+	iterator, metadata, err := ctx.GetStub().GetStateByPartialCompositeKeyWithPagination(ticketByEventStatusIndex, []string{eventID, string(status)}, pageSize, bookmark)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query tickets for event %s with status %s: %v", eventID, status, err)
+	}
+	defer iterator.Close()
+
+	tickets, err := resolveTicketsFromIndex(ctx, iterator, ticketByEventStatusIndex)
+	if err != nil {
+		return nil, err
+	}
+
+	return &TicketQueryResult{Tickets: tickets, Bookmark: metadata.GetBookmark()}, nil
+}
+
+// QueryEventsByHost returns a page of events hosted by hostID, using the
+// event~host~id index.
+func (ctbc *ConcertTicketBookingChaincode) QueryEventsByHost(ctx contractapi.TransactionContextInterface, hostID string, pageSize int32, bookmark string) (*EventQueryResult, error) {
+	iterator, metadata, err := ctx.GetStub().GetStateByPartialCompositeKeyWithPagination(eventByHostIndex, []string{hostID}, pageSize, bookmark)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query events for host %s: %v", hostID, err)
+	}
+	defer iterator.Close()
+
+	events := []*Event{}
+	for iterator.HasNext() {
+		item, err := iterator.Next()
+		if err != nil {
+			return nil, fmt.Errorf("failed to iterate event index: %v", err)
+		}
+
+		_, parts, err := ctx.GetStub().SplitCompositeKey(item.Key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to split composite key %s: %v", item.Key, err)
+		}
+		eventID := parts[len(parts)-1]
+
+		eventBytes, err := ctx.GetStub().GetState(eventID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read event %s: %v", eventID, err)
+		}
+		if eventBytes == nil {
+			continue
+		}
+
+		event := &Event{}
+		if err := bytesToEvent(eventBytes, event); err != nil {
+			return nil, err
+		}
+		events = append(events, event)
+	}
+
+	return &EventQueryResult{Events: events, Bookmark: metadata.GetBookmark()}, nil
+}
+
+// resolveTicketsFromIndex reads back the full Ticket record for each entry
+// returned by a ticket composite-key iterator, deriving the ticket ID from
+// the last segment of the composite key.
+func resolveTicketsFromIndex(ctx contractapi.TransactionContextInterface, iterator shim.StateQueryIteratorInterface, objectType string) ([]*Ticket, error) {
+	tickets := []*Ticket{}
+	for iterator.HasNext() {
+		item, err := iterator.Next()
+		if err != nil {
+			return nil, fmt.Errorf("failed to iterate ticket index: %v", err)
+		}
+
+		_, parts, err := ctx.GetStub().SplitCompositeKey(item.Key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to split composite key %s: %v", item.Key, err)
+		}
+		ticketID := parts[len(parts)-1]
+
+		ticketBytes, err := ctx.GetStub().GetState(ticketID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read ticket %s: %v", ticketID, err)
+		}
+		if ticketBytes == nil {
+			continue
+		}
+
+		ticket := &Ticket{}
+		if err := bytesToTicket(ticketBytes, ticket); err != nil {
+			return nil, err
+		}
+		tickets = append(tickets, ticket)
+	}
+
+	return tickets, nil
+}
+
+// putTicketByEventStatusIndex writes the ticket~event~status~id index entry
+// matching the ticket's current status.
+func putTicketByEventStatusIndex(ctx contractapi.TransactionContextInterface, ticket *Ticket) error {
+	key, err := ctx.GetStub().CreateCompositeKey(ticketByEventStatusIndex, []string{ticket.EventID, string(ticket.Status), ticket.ID})
+	if err != nil {
+		return fmt.Errorf("failed to create ticket event/status index key: %v", err)
+	}
+	if err := ctx.GetStub().PutState(key, []byte{0x00}); err != nil {
+		return fmt.Errorf("failed to put ticket event/status index: %v", err)
+	}
+	return nil
+}
+
+// deleteTicketByEventStatusIndex removes the ticket~event~status~id index
+// entry matching the ticket's current (about-to-be-replaced) status.
+func deleteTicketByEventStatusIndex(ctx contractapi.TransactionContextInterface, ticket *Ticket) error {
+	key, err := ctx.GetStub().CreateCompositeKey(ticketByEventStatusIndex, []string{ticket.EventID, string(ticket.Status), ticket.ID})
+	if err != nil {
+		return fmt.Errorf("failed to create ticket event/status index key: %v", err)
+	}
+	if err := ctx.GetStub().DelState(key); err != nil {
+		return fmt.Errorf("failed to delete ticket event/status index: %v", err)
+	}
+	return nil
+}
+
+// putTicketByOwnerIndex writes the ticket~owner~id index entry for the
+// ticket's current owner. Tickets with no owner yet are not indexed.
+func putTicketByOwnerIndex(ctx contractapi.TransactionContextInterface, ticket *Ticket) error {
+	if ticket.Owner == "" {
+		return nil
+	}
+	key, err := ctx.GetStub().CreateCompositeKey(ticketByOwnerIndex, []string{ticket.Owner, ticket.ID})
+	if err != nil {
+		return fmt.Errorf("failed to create ticket owner index key: %v", err)
+	}
+	if err := ctx.GetStub().PutState(key, []byte{0x00}); err != nil {
+		return fmt.Errorf("failed to put ticket owner index: %v", err)
+	}
+	return nil
+}
+
+// deleteTicketByOwnerIndex removes the ticket~owner~id index entry for the
+// ticket's current (about-to-change) owner.
+func deleteTicketByOwnerIndex(ctx contractapi.TransactionContextInterface, ticket *Ticket) error {
+	if ticket.Owner == "" {
+		return nil
+	}
+	key, err := ctx.GetStub().CreateCompositeKey(ticketByOwnerIndex, []string{ticket.Owner, ticket.ID})
+	if err != nil {
+		return fmt.Errorf("failed to create ticket owner index key: %v", err)
+	}
+	if err := ctx.GetStub().DelState(key); err != nil {
+		return fmt.Errorf("failed to delete ticket owner index: %v", err)
+	}
+	return nil
+}
+
+// parseECDSAPublicKey decodes a PEM-encoded PKIX public key and asserts
+// that it is a secp256r1 (P-256) ECDSA key.
+func parseECDSAPublicKey(pubKeyPEM string) (*ecdsa.PublicKey, error) {
+	block, _ := pem.Decode([]byte(pubKeyPEM))
+	if block == nil {
+		return nil, fmt.Errorf("failed to decode PEM block")
+	}
+
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse public key: %v", err)
+	}
+
+	ecdsaKey, ok := key.(*ecdsa.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("public key is not an ECDSA key")
+	}
+	if ecdsaKey.Curve != elliptic.P256() {
+		return nil, fmt.Errorf("public key is not on the secp256r1 (P-256) curve")
+	}
+
+	return ecdsaKey, nil
+}
+
+// verifyTransferSignature checks that sigHex is a valid detached ECDSA
+// signature, produced by the holder of ownerPubKeyPEM, over
+// "ticketID:newOwnerID:nonce:expiry".
+func verifyTransferSignature(ownerPubKeyPEM, ticketID, newOwnerID string, nonce uint64, expiry int64, sigHex string) error {
+	pubKey, err := parseECDSAPublicKey(ownerPubKeyPEM)
+	if err != nil {
+		return err
+	}
+
+	sig, err := hex.DecodeString(sigHex)
+	if err != nil {
+		return fmt.Errorf("failed to decode signature hex: %v", err)
+	}
+
+	half := len(sig) / 2
+	if half == 0 || len(sig)%2 != 0 {
+		return fmt.Errorf("signature has an invalid length")
+	}
+	r := new(big.Int).SetBytes(sig[:half])
+	s := new(big.Int).SetBytes(sig[half:])
+
+	message := fmt.Sprintf("%s:%s:%d:%d", ticketID, newOwnerID, nonce, expiry)
+	digest := sha256.Sum256([]byte(message))
+
+	if !ecdsa.Verify(pubKey, digest[:], r, s) {
+		return fmt.Errorf("signature verification failed")
+	}
+
+	return nil
+}
+
+// putEventByHostIndex writes the event~host~id index entry for a newly
+// created event.
+func putEventByHostIndex(ctx contractapi.TransactionContextInterface, hostID, eventID string) error {
+	key, err := ctx.GetStub().CreateCompositeKey(eventByHostIndex, []string{hostID, eventID})
+	if err != nil {
+		return fmt.Errorf("failed to create event host index key: %v", err)
+	}
+	if err := ctx.GetStub().PutState(key, []byte{0x00}); err != nil {
+		return fmt.Errorf("failed to put event host index: %v", err)
+	}
+	return nil
+}
+
+// putImportedTicketHistory persists the GetHistoryForKey provenance
+// ExportEvent captured for a ticket, keyed by that ticket's new (imported)
+// ID, so the history isn't lost even though it cannot be replayed into
+// this channel's own GetHistoryForKey log.
+func putImportedTicketHistory(ctx contractapi.TransactionContextInterface, ticketID string, history []TicketHistoryEntry) error {
+	key, err := ctx.GetStub().CreateCompositeKey(ticketImportedHistoryKey, []string{ticketID})
+	if err != nil {
+		return fmt.Errorf("failed to create ticket history key: %v", err)
+	}
+
+	historyBytes, err := json.Marshal(history)
+	if err != nil {
+		return fmt.Errorf("failed to marshal ticket history: %v", err)
+	}
+
+	if err := ctx.GetStub().PutState(key, historyBytes); err != nil {
+		return fmt.Errorf("failed to put ticket history: %v", err)
+	}
+	return nil
+}
+
+// GetImportedTicketHistory returns the pre-import provenance recorded for
+// ticketID by ImportEvent, or an empty slice if ticketID was not created
+// via ImportEvent (or carried no history).
+func (ctbc *ConcertTicketBookingChaincode) GetImportedTicketHistory(ctx contractapi.TransactionContextInterface, ticketID string) ([]TicketHistoryEntry, error) {
+	key, err := ctx.GetStub().CreateCompositeKey(ticketImportedHistoryKey, []string{ticketID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create ticket history key: %v", err)
+	}
+
+	historyBytes, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read ticket history for %s: %v", ticketID, err)
+	}
+
+	history := []TicketHistoryEntry{}
+	if historyBytes == nil {
+		return history, nil
+	}
+	if err := json.Unmarshal(historyBytes, &history); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal ticket history: %v", err)
+	}
+	return history, nil
+}
+
 // participantToBytes converts a participant to a byte array
 func participantToBytes(participant *Participant) []byte {
 	participantBytes, _ := json.Marshal(participant)